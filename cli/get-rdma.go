@@ -52,6 +52,19 @@ var getRDMAFlags = []cli.Flag{
 		Name:  "gpu",
 		Usage: "allocate buffers on GPU memory for RDMA",
 	},
+	cli.StringFlag{
+		Name:  "bandwidth",
+		Usage: "Target aggregate bandwidth across all workers, e.g. 100MiB or 10Gbps. Unlimited if not set.",
+	},
+	cli.StringFlag{
+		Name:  "rdma-pool-mem",
+		Value: "0",
+		Usage: "Cap on total registered memory used by the RDMA buffer pool. 0 is unbounded.",
+	},
+	cli.BoolFlag{
+		Name:  "verify",
+		Usage: "Seed uploaded objects with a deterministic pattern and verify downloaded bytes against it, reporting any mismatch as an operation error.",
+	},
 }
 
 // GetRDMA command.
@@ -83,6 +96,7 @@ func mainGetRDMA(ctx *cli.Context) error {
 		ListFlat:      ctx.Bool("list-flat"),
 		ListPrefix:    ctx.String("prefix"),
 		CreateObjects: ctx.Int("objects"),
+		Verify:        ctx.Bool("verify"),
 	}
 	b.Common.GPU = ctx.Bool("gpu")
 	b.CliCtx = ctx
@@ -98,6 +112,9 @@ func checkGetRDMASyntax(ctx *cli.Context) {
 	if ctx.NArg() > 0 {
 		console.Fatal("Command takes no arguments")
 	}
+	if ctx.Bool("gpu") && ctx.Bool("verify") {
+		console.Fatal("--verify reads buffers as host memory and cannot be combined with --gpu")
+	}
 
 	checkAnalyze(ctx)
 	checkBenchmark(ctx)
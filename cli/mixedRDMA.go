@@ -0,0 +1,118 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package cli
+
+import (
+	"github.com/minio/cli"
+	"github.com/minio/pkg/v2/console"
+	"github.com/minio/warp/pkg/bench"
+)
+
+var mixedRDMAFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "obj.size",
+		Value: "10MiB",
+		Usage: "Size of each generated object. Can be a number or 10KiB/MiB/GiB. All sizes are base 2 binary.",
+	},
+	cli.IntFlag{
+		Name:  "objects",
+		Value: 2500,
+		Usage: "Number of objects to upload before the benchmark starts.",
+	},
+	cli.IntFlag{
+		Name:  "put-distrib",
+		Value: 45,
+		Usage: "Distribution of PUT operations, in percent.",
+	},
+	cli.IntFlag{
+		Name:  "get-distrib",
+		Value: 45,
+		Usage: "Distribution of GET operations, in percent.",
+	},
+	cli.IntFlag{
+		Name:  "stat-distrib",
+		Value: 5,
+		Usage: "Distribution of STAT operations, in percent.",
+	},
+	cli.IntFlag{
+		Name:  "delete-distrib",
+		Value: 5,
+		Usage: "Distribution of DELETE operations, in percent.",
+	},
+	cli.BoolFlag{
+		Name:  "gpu",
+		Usage: "allocate buffers on GPU memory for RDMA",
+	},
+	cli.StringFlag{
+		Name:  "bandwidth",
+		Usage: "Target aggregate bandwidth across all workers, e.g. 100MiB or 10Gbps. Unlimited if not set.",
+	},
+	cli.StringFlag{
+		Name:  "rdma-pool-mem",
+		Value: "0",
+		Usage: "Cap on total registered memory used by the RDMA buffer pool. 0 is unbounded.",
+	},
+}
+
+// MixedRDMA command.
+var mixedRDMACmd = cli.Command{
+	Name:   "mixedRDMA",
+	Usage:  "benchmark mixed put/get/stat/delete objects over RDMA",
+	Action: mainMixedRDMA,
+	Before: setGlobalsFromContext,
+	Flags:  combineFlags(globalFlags, ioFlags, mixedRDMAFlags, genFlags, benchFlags, analyzeFlags),
+	CustomHelpTemplate: `NAME:
+  {{.HelpName}} - {{.Usage}}
+
+USAGE:
+  {{.HelpName}} [FLAGS]
+  -> see https://github.com/minio/warp#mixedRDMA
+
+FLAGS:
+  {{range .VisibleFlags}}{{.}}
+  {{end}}`,
+}
+
+// mainMixedRDMA is the entry point for mixedRDMA command.
+func mainMixedRDMA(ctx *cli.Context) error {
+	checkMixedRDMASyntax(ctx)
+	b := bench.MixedRDMA{
+		Common:        getCommon(ctx, newGenSource(ctx, "obj.size")),
+		CreateObjects: ctx.Int("objects"),
+		PutDistrib:    ctx.Int("put-distrib"),
+		GetDistrib:    ctx.Int("get-distrib"),
+		StatDistrib:   ctx.Int("stat-distrib"),
+		DeleteDistrib: ctx.Int("delete-distrib"),
+	}
+	b.Common.GPU = ctx.Bool("gpu")
+	b.CliCtx = ctx
+	return runBench(ctx, &b)
+}
+
+func checkMixedRDMASyntax(ctx *cli.Context) {
+	if ctx.NArg() > 0 {
+		console.Fatal("Command takes no arguments")
+	}
+
+	if d := ctx.Int("put-distrib") + ctx.Int("get-distrib") + ctx.Int("stat-distrib") + ctx.Int("delete-distrib"); d != 100 {
+		console.Fatal("sum of put-distrib, get-distrib, stat-distrib and delete-distrib must be 100, got ", d)
+	}
+
+	checkAnalyze(ctx)
+	checkBenchmark(ctx)
+}
@@ -34,6 +34,15 @@ var putRDMAFlags = []cli.Flag{
 		Name:  "gpu",
 		Usage: "allocate buffers on GPU memory for RDMA",
 	},
+	cli.StringFlag{
+		Name:  "bandwidth",
+		Usage: "Target aggregate bandwidth across all workers, e.g. 100MiB or 10Gbps. Unlimited if not set.",
+	},
+	cli.StringFlag{
+		Name:  "rdma-pool-mem",
+		Value: "0",
+		Usage: "Cap on total registered memory used by the RDMA buffer pool. 0 is unbounded.",
+	},
 }
 
 // PutRDMA command.
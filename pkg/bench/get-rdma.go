@@ -18,13 +18,13 @@
 package bench
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/rand"
 	"net/http"
 	"sync"
 	"time"
-	"unsafe"
 
 	"github.com/dustin/go-humanize"
 	"github.com/minio/minio-go/v7"
@@ -41,12 +41,19 @@ type GetRDMA struct {
 	ListPrefix string
 
 	objects       generator.Objects
+	pool          *RDMABufferPool
 	CreateObjects int
 	Versions      int
 	RandomRanges  bool
 	RangeSize     int64
 	ListExisting  bool
 	ListFlat      bool
+	Verify        bool
+
+	// RateMonitor enforces the --bandwidth cap and tracks live throughput
+	// while Start runs. It is set by Start only when --bandwidth is given;
+	// otherwise it stays nil and no throughput is tracked.
+	RateMonitor *RateMonitor
 }
 
 // Prepare will create an empty bucket or delete any content already there
@@ -152,35 +159,21 @@ func (g *GetRDMA) Prepare(ctx context.Context) error {
 		return err
 	}
 
+	poolMem, err := humanize.ParseBytes(g.CliCtx.String("rdma-pool-mem"))
+	if err != nil {
+		return err
+	}
+	g.pool = NewRDMABufferPool(poolMem, g.GPU)
+
 	for i, obj := range objs {
 		go func(i int, obj []struct{}) {
 			defer wg.Done()
 			src := g.Source()
 			opts := g.PutOpts
 
-			rbuf := make([]byte, 1)
-			rand.Read(rbuf)
-
-			var buf unsafe.Pointer
-			if g.GPU {
-				buf = minio.AlignedGPU(int(size), rbuf[0])
-			} else {
-				buf = minio.Aligned(int(size), rbuf[0])
-			}
-
 			client, cldone := g.Client()
 			defer cldone()
 
-			free := func(buf unsafe.Pointer) {
-				if g.GPU {
-					minio.FreeGPU(buf)
-				} else {
-					minio.Free(buf)
-				}
-			}
-
-			defer free(buf)
-
 			for range obj {
 				select {
 				case <-ctx.Done():
@@ -198,10 +191,31 @@ func (g *GetRDMA) Prepare(ctx context.Context) error {
 					// New input for each version
 					obj := src.Object()
 					obj.Name = name
+					objSize := obj.Size
+					if objSize <= 0 {
+						objSize = int64(size)
+					}
+
+					buf, release, err := g.pool.Get(int(objSize))
+					if err != nil {
+						err = fmt.Errorf("buffer pool: %w", err)
+						g.Error(err)
+						mu.Lock()
+						if groupErr == nil {
+							groupErr = err
+						}
+						mu.Unlock()
+						return
+					}
+
+					if g.Verify {
+						verifyPattern(bufferBytes(buf, int(objSize)), obj.Name, 0)
+					}
+
 					op := Operation{
 						OpType:   http.MethodPut,
 						Thread:   uint16(i),
-						Size:     obj.Size,
+						Size:     objSize,
 						File:     obj.Name,
 						ObjPerOp: 1,
 						Endpoint: client.GoClient.EndpointURL().String(),
@@ -209,8 +223,9 @@ func (g *GetRDMA) Prepare(ctx context.Context) error {
 
 					opts.ContentType = obj.ContentType
 					op.Start = time.Now()
-					res, err := client.PutObjectRDMA(ctx, g.Bucket, obj.Name, buf, int(size), opts)
+					res, err := client.PutObjectRDMA(ctx, g.Bucket, obj.Name, buf, int(objSize), opts)
 					op.End = time.Now()
+					release()
 					if err != nil {
 						err := fmt.Errorf("upload error: %w", err)
 						g.Error(err)
@@ -222,8 +237,8 @@ func (g *GetRDMA) Prepare(ctx context.Context) error {
 						return
 					}
 					obj.VersionID = res.VersionID
-					if res.Size != int64(size) {
-						err := fmt.Errorf("short upload. want: %d, got %d", obj.Size, res.Size)
+					if res.Size != objSize {
+						err := fmt.Errorf("short upload. want: %d, got %d", objSize, res.Size)
 						g.Error(err)
 						mu.Lock()
 						if groupErr == nil {
@@ -261,6 +276,23 @@ func (g *GetRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 		return Operations{}, err
 	}
 
+	if bw := g.CliCtx.String("bandwidth"); bw != "" {
+		rate, err := ParseBandwidth(bw)
+		if err != nil {
+			return Operations{}, err
+		}
+		g.RateMonitor = NewRateMonitor(rate, 0)
+	}
+
+	poolMem, err := humanize.ParseBytes(g.CliCtx.String("rdma-pool-mem"))
+	if err != nil {
+		return Operations{}, err
+	}
+	if g.pool == nil {
+		g.pool = NewRDMABufferPool(poolMem, g.GPU)
+	}
+	defer g.pool.Close()
+
 	// Non-terminating context.
 	nonTerm := context.Background()
 	var groupErr error
@@ -275,26 +307,9 @@ func (g *GetRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 
 			<-wait
 
-			var buf unsafe.Pointer
-			if g.GPU {
-				buf = minio.AlignedGPU(int(size), ' ')
-			} else {
-				buf = minio.Aligned(int(size), ' ')
-			}
-
 			client, cldone := g.Client()
 			defer cldone()
 
-			free := func(buf unsafe.Pointer) {
-				if g.GPU {
-					minio.FreeGPU(buf)
-				} else {
-					minio.Free(buf)
-				}
-			}
-
-			defer free(buf)
-
 			for {
 				select {
 				case <-done:
@@ -307,10 +322,14 @@ func (g *GetRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 				}
 
 				obj := g.objects[rng.Intn(len(g.objects))]
+				objSize := obj.Size
+				if objSize <= 0 {
+					objSize = int64(size)
+				}
 				op := Operation{
 					OpType:   http.MethodGet,
 					Thread:   uint16(i),
-					Size:     obj.Size,
+					Size:     objSize,
 					File:     obj.Name,
 					ObjPerOp: 1,
 					Endpoint: client.GoClient.EndpointURL().String(),
@@ -319,45 +338,73 @@ func (g *GetRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 					op.File = ""
 				}
 
+				var rangeStart int64
 				if g.RandomRanges && op.Size > 2 {
-					var start, end int64
+					var end int64
 					if g.RangeSize <= 0 {
 						// Randomize length similar to --obj.randsize
 						size := generator.GetExpRandSize(rng, 0, op.Size-2)
-						start = rng.Int63n(op.Size - size)
-						end = start + size
+						rangeStart = rng.Int63n(op.Size - size)
+						end = rangeStart + size
 					} else {
-						start = rng.Int63n(op.Size - g.RangeSize)
-						end = start + g.RangeSize - 1
+						rangeStart = rng.Int63n(op.Size - g.RangeSize)
+						end = rangeStart + g.RangeSize - 1
 					}
-					op.Size = end - start + 1
-					opts.SetRange(start, end)
+					op.Size = end - rangeStart + 1
+					opts.SetRange(rangeStart, end)
+				}
+
+				g.RateMonitor.Limit(ctx, op.Size)
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				buf, release, err := g.pool.Get(int(op.Size))
+				if err != nil {
+					g.Error("buffer pool: ", err)
+					op.Err = err.Error()
+					rcv <- op
+					continue
 				}
+
 				op.Start = time.Now()
-				var err error
 				if g.Versions > 1 {
 					opts.VersionID = obj.VersionID
 				}
 
-				err = client.GetObjectRDMA(nonTerm, g.Bucket, obj.Name, buf, int(size), opts)
+				err = client.GetObjectRDMA(nonTerm, g.Bucket, obj.Name, buf, int(op.Size), opts)
+				op.End = time.Now()
 				if err != nil {
+					release()
 					g.Error("download error:", err)
 					op.Err = err.Error()
-					op.End = time.Now()
 					rcv <- op
 					continue
 				}
 				op.FirstByte = &op.Start
-				op.End = time.Now()
-				if int64(size) != op.Size && op.Err == "" {
-					op.Err = fmt.Sprint("unexpected download size. want:", op.Size, ", got:", size)
-					g.Error(op.Err)
+
+				if g.Verify {
+					got := bufferBytes(buf, int(op.Size))
+					want := make([]byte, op.Size)
+					verifyPattern(want, obj.Name, rangeStart)
+					if !bytes.Equal(got, want) {
+						op.Err = fmt.Sprintf("checksum mismatch for %s [%d:%d)", obj.Name, rangeStart, rangeStart+op.Size)
+						g.Error(op.Err)
+					}
 				}
+				release()
 				rcv <- op
 			}
 		}(i)
 	}
 	wg.Wait()
+	g.RateMonitor.logStats()
+	rcv := c.Receiver()
+	for _, op := range g.RateMonitor.statsOps() {
+		rcv <- op
+	}
 	return c.Close(), groupErr
 }
 
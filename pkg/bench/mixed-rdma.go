@@ -0,0 +1,440 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/pkg/v2/console"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// MixedRDMA benchmarks a mix of PUT/GET/STAT/DELETE over RDMA against a
+// shared, pre-populated object set, analogous to Mixed but driving
+// PutObjectRDMA/GetObjectRDMA instead of the regular S3 data path.
+type MixedRDMA struct {
+	Common
+
+	CreateObjects int
+	PutDistrib    int
+	GetDistrib    int
+	StatDistrib   int
+	DeleteDistrib int
+
+	pool *RDMABufferPool
+
+	mu      sync.Mutex
+	objects generator.Objects
+
+	// RateMonitor enforces the --bandwidth cap and tracks live throughput
+	// while Start runs. It is set by Start only when --bandwidth is given;
+	// otherwise it stays nil and no throughput is tracked.
+	RateMonitor *RateMonitor
+}
+
+// Prepare will create an empty bucket or delete any content already
+// there, then upload CreateObjects objects to seed the mixed workload.
+func (m *MixedRDMA) Prepare(ctx context.Context) error {
+	if err := m.createEmptyBucket(ctx); err != nil {
+		return err
+	}
+	m.addCollector()
+
+	console.Eraseline()
+	console.Info("\rUploading ", m.CreateObjects, " objects")
+
+	size, err := humanize.ParseBytes(m.CliCtx.String("obj.size"))
+	if err != nil {
+		return err
+	}
+	poolMem, err := humanize.ParseBytes(m.CliCtx.String("rdma-pool-mem"))
+	if err != nil {
+		return err
+	}
+	m.pool = NewRDMABufferPool(poolMem, m.GPU)
+
+	var wg sync.WaitGroup
+	wg.Add(m.Concurrency)
+
+	objs := splitObjs(m.CreateObjects, m.Concurrency)
+	rcv := m.Collector.rcv
+	var groupErr error
+	var mu sync.Mutex
+
+	for i, obj := range objs {
+		go func(i int, obj []struct{}) {
+			defer wg.Done()
+			src := m.Source()
+			opts := m.PutOpts
+
+			client, cldone := m.Client()
+			defer cldone()
+
+			for range obj {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				gen := src.Object()
+				objSize := gen.Size
+				if objSize <= 0 {
+					objSize = int64(size)
+				}
+
+				buf, release, err := m.pool.Get(int(objSize))
+				if err != nil {
+					err = fmt.Errorf("buffer pool: %w", err)
+					m.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+
+				opts.ContentType = gen.ContentType
+				op := Operation{
+					OpType:   http.MethodPut,
+					Thread:   uint16(i),
+					Size:     objSize,
+					File:     gen.Name,
+					ObjPerOp: 1,
+					Endpoint: client.GoClient.EndpointURL().String(),
+				}
+
+				op.Start = time.Now()
+				res, err := client.PutObjectRDMA(ctx, m.Bucket, gen.Name, buf, int(objSize), opts)
+				op.End = time.Now()
+				release()
+				if err != nil {
+					err = fmt.Errorf("upload error: %w", err)
+					m.Error(err)
+					mu.Lock()
+					if groupErr == nil {
+						groupErr = err
+					}
+					mu.Unlock()
+					return
+				}
+				gen.VersionID = res.VersionID
+				gen.Reader = nil
+
+				m.mu.Lock()
+				m.objects = append(m.objects, gen)
+				n := len(m.objects)
+				m.mu.Unlock()
+
+				mu.Lock()
+				m.prepareProgress(float64(n) / float64(m.CreateObjects))
+				mu.Unlock()
+				rcv <- op
+			}
+		}(i, obj)
+	}
+	wg.Wait()
+	return groupErr
+}
+
+// mixedRDMAOp picks an operation type for one iteration of the mix,
+// weighted by the configured distribution percentages.
+func mixedRDMAOp(rng *rand.Rand, put, get, stat, del int) string {
+	switch n := rng.Intn(100); {
+	case n < put:
+		return http.MethodPut
+	case n < put+get:
+		return http.MethodGet
+	case n < put+get+stat:
+		return http.MethodHead
+	default:
+		return http.MethodDelete
+	}
+}
+
+// Start will execute the main benchmark.
+// Operations should begin executing when the start channel is closed.
+func (m *MixedRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	wg.Add(m.Concurrency)
+	c := m.Collector
+	if m.AutoTermDur > 0 {
+		ctx = c.AutoTerm(ctx, http.MethodPut, m.AutoTermScale, autoTermCheck, autoTermSamples, m.AutoTermDur)
+	}
+
+	size, err := humanize.ParseBytes(m.CliCtx.String("obj.size"))
+	if err != nil {
+		return Operations{}, err
+	}
+
+	if bw := m.CliCtx.String("bandwidth"); bw != "" {
+		rate, err := ParseBandwidth(bw)
+		if err != nil {
+			return Operations{}, err
+		}
+		m.RateMonitor = NewRateMonitor(rate, 0)
+	}
+	if m.pool == nil {
+		poolMem, err := humanize.ParseBytes(m.CliCtx.String("rdma-pool-mem"))
+		if err != nil {
+			return Operations{}, err
+		}
+		m.pool = NewRDMABufferPool(poolMem, m.GPU)
+	}
+	defer m.pool.Close()
+
+	// Non-terminating context.
+	nonTerm := context.Background()
+	var groupErr error
+
+	for i := 0; i < m.Concurrency; i++ {
+		go func(i int) {
+			rng := rand.New(rand.NewSource(int64(i)))
+			rcv := c.Receiver()
+			defer wg.Done()
+
+			src := m.Source()
+			putOpts := m.PutOpts
+			done := ctx.Done()
+
+			<-wait
+
+			client, cldone := m.Client()
+			defer cldone()
+
+			// pickObject returns a random existing object, holding m.mu only
+			// for the snapshot check and copy so the length can never go
+			// stale between checking and indexing.
+			pickObject := func() (generator.Object, bool) {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				if len(m.objects) == 0 {
+					return generator.Object{}, false
+				}
+				return m.objects[rng.Intn(len(m.objects))], true
+			}
+
+			// popObject removes and returns a random existing object under
+			// the same lock that checks for emptiness, so a draining
+			// object set can never be indexed past empty.
+			popObject := func() (generator.Object, bool) {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				if len(m.objects) == 0 {
+					return generator.Object{}, false
+				}
+				idx := rng.Intn(len(m.objects))
+				obj := m.objects[idx]
+				m.objects[idx] = m.objects[len(m.objects)-1]
+				m.objects = m.objects[:len(m.objects)-1]
+				return obj, true
+			}
+
+			// doPut returns true if ctx was canceled while it was waiting
+			// on RateMonitor.Limit, in which case the caller should return
+			// from the worker without sending op.
+			doPut := func(op *Operation) bool {
+				op.OpType = http.MethodPut
+
+				gen := src.Object()
+				objSize := gen.Size
+				if objSize <= 0 {
+					objSize = int64(size)
+				}
+
+				m.RateMonitor.Limit(ctx, objSize)
+				select {
+				case <-done:
+					return true
+				default:
+				}
+
+				buf, release, err := m.pool.Get(int(objSize))
+				if err != nil {
+					m.Error("buffer pool: ", err)
+					op.Err = err.Error()
+					return false
+				}
+
+				putOpts.ContentType = gen.ContentType
+				op.Size = objSize
+				op.File = gen.Name
+
+				op.Start = time.Now()
+				res, err := client.PutObjectRDMA(nonTerm, m.Bucket, gen.Name, buf, int(objSize), putOpts)
+				op.End = time.Now()
+				release()
+				if err != nil {
+					m.Error("upload error: ", err)
+					op.Err = err.Error()
+					return false
+				}
+				if res.Size != objSize {
+					err := fmt.Sprintf("short upload. want: %d, got %d", objSize, res.Size)
+					m.Error(err)
+					op.Err = err
+					return false
+				}
+
+				gen.VersionID = res.VersionID
+				gen.Reader = nil
+				m.mu.Lock()
+				m.objects = append(m.objects, gen)
+				m.mu.Unlock()
+				return false
+			}
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				if m.rpsLimit(ctx) != nil {
+					return
+				}
+
+				opType := mixedRDMAOp(rng, m.PutDistrib, m.GetDistrib, m.StatDistrib, m.DeleteDistrib)
+
+				op := Operation{
+					OpType:   opType,
+					Thread:   uint16(i),
+					ObjPerOp: 1,
+					Endpoint: client.GoClient.EndpointURL().String(),
+				}
+
+				switch opType {
+				case http.MethodPut:
+					if doPut(&op) {
+						return
+					}
+
+				case http.MethodGet:
+					obj, ok := pickObject()
+					if !ok {
+						if doPut(&op) {
+							return
+						}
+						break
+					}
+
+					objSize := obj.Size
+					if objSize <= 0 {
+						objSize = int64(size)
+					}
+
+					m.RateMonitor.Limit(ctx, objSize)
+					select {
+					case <-done:
+						return
+					default:
+					}
+
+					buf, release, err := m.pool.Get(int(objSize))
+					if err != nil {
+						m.Error("buffer pool: ", err)
+						op.Err = err.Error()
+						break
+					}
+
+					op.Size = objSize
+					op.File = obj.Name
+
+					getOpts := minio.GetObjectOptions{}
+					if obj.VersionID != "" {
+						getOpts.VersionID = obj.VersionID
+					}
+
+					op.Start = time.Now()
+					err = client.GetObjectRDMA(nonTerm, m.Bucket, obj.Name, buf, int(objSize), getOpts)
+					release()
+					op.End = time.Now()
+					if err != nil {
+						m.Error("download error: ", err)
+						op.Err = err.Error()
+					} else {
+						op.FirstByte = &op.Start
+					}
+
+				case http.MethodHead:
+					obj, ok := pickObject()
+					if !ok {
+						if doPut(&op) {
+							return
+						}
+						break
+					}
+
+					op.Size = obj.Size
+					op.File = obj.Name
+
+					op.Start = time.Now()
+					_, err := client.GoClient.StatObject(nonTerm, m.Bucket, obj.Name, minio.StatObjectOptions{VersionID: obj.VersionID})
+					op.End = time.Now()
+					if err != nil {
+						m.Error("stat error: ", err)
+						op.Err = err.Error()
+					}
+
+				case http.MethodDelete:
+					obj, ok := popObject()
+					if !ok {
+						if doPut(&op) {
+							return
+						}
+						break
+					}
+
+					op.Size = obj.Size
+					op.File = obj.Name
+
+					op.Start = time.Now()
+					err := client.GoClient.RemoveObject(nonTerm, m.Bucket, obj.Name, minio.RemoveObjectOptions{VersionID: obj.VersionID})
+					op.End = time.Now()
+					if err != nil {
+						m.Error("delete error: ", err)
+						op.Err = err.Error()
+					}
+				}
+
+				rcv <- op
+			}
+		}(i)
+	}
+	wg.Wait()
+	m.RateMonitor.logStats()
+	rcv := c.Receiver()
+	for _, op := range m.RateMonitor.statsOps() {
+		rcv <- op
+	}
+	return c.Close(), groupErr
+}
+
+// Cleanup deletes everything uploaded to the bucket.
+func (m *MixedRDMA) Cleanup(ctx context.Context) {
+	m.deleteAllInBucket(ctx, m.objects.Prefixes()...)
+}
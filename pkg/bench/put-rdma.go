@@ -23,14 +23,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 	"sync"
 	"time"
-	"unsafe"
 
 	"github.com/dustin/go-humanize"
 	"github.com/minio/cli"
@@ -45,6 +43,12 @@ type PutRDMA struct {
 	Common
 	prefixes map[string]struct{}
 	cl       *http.Client
+	pool     *RDMABufferPool
+
+	// RateMonitor enforces the --bandwidth cap and tracks live throughput
+	// while Start runs. It is set by Start only when --bandwidth is given;
+	// otherwise it stays nil and no throughput is tracked.
+	RateMonitor *RateMonitor
 }
 
 // Prepare will create an empty bucket ot delete any content already there.
@@ -136,6 +140,21 @@ func (u *PutRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 		return Operations{}, err
 	}
 
+	if bw := u.CliCtx.String("bandwidth"); bw != "" {
+		rate, err := ParseBandwidth(bw)
+		if err != nil {
+			return Operations{}, err
+		}
+		u.RateMonitor = NewRateMonitor(rate, 0)
+	}
+
+	poolMem, err := humanize.ParseBytes(u.CliCtx.String("rdma-pool-mem"))
+	if err != nil {
+		return Operations{}, err
+	}
+	u.pool = NewRDMABufferPool(poolMem, u.GPU)
+	defer u.pool.Close()
+
 	// Non-terminating context.
 	nonTerm := context.Background()
 	var groupErr error
@@ -147,32 +166,13 @@ func (u *PutRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 
 			opts := u.PutOpts
 			done := ctx.Done()
+			src := u.Source()
 
 			<-wait
 
-			rbuf := make([]byte, 1)
-			rand.Read(rbuf)
-
-			var buf unsafe.Pointer
-			if u.GPU {
-				buf = minio.AlignedGPU(int(size), rbuf[0])
-			} else {
-				buf = minio.Aligned(int(size), rbuf[0])
-			}
-
 			client, cldone := u.Client()
 			defer cldone()
 
-			free := func(buf unsafe.Pointer) {
-				if u.GPU {
-					minio.FreeGPU(buf)
-				} else {
-					minio.Free(buf)
-				}
-			}
-
-			defer free(buf)
-
 			j := 1
 			for {
 				select {
@@ -185,26 +185,48 @@ func (u *PutRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 					return
 				}
 
+				objSize := int64(size)
+				if randSize := src.Object().Size; randSize > 0 {
+					objSize = randSize
+				}
+
+				u.RateMonitor.Limit(ctx, objSize)
+				select {
+				case <-done:
+					return
+				default:
+				}
+
 				objName := fmt.Sprintf("%d-xx-%d/testobject-obj%d-worker%d.txt", j, i, j, i)
 				op := Operation{
 					OpType:   http.MethodPut,
 					Thread:   uint16(i),
-					Size:     int64(size),
+					Size:     objSize,
 					ObjPerOp: 1,
 					File:     objName,
 					Endpoint: client.GoClient.EndpointURL().String(),
 				}
 
+				buf, release, err := u.pool.Get(int(objSize))
+				if err != nil {
+					u.Error("buffer pool: ", err)
+					op.Err = err.Error()
+					rcv <- op
+					j++
+					continue
+				}
+
 				op.Start = time.Now()
-				res, err := client.PutObjectRDMA(nonTerm, u.Bucket, objName, buf, int(size), opts)
+				res, err := client.PutObjectRDMA(nonTerm, u.Bucket, objName, buf, int(objSize), opts)
 				op.End = time.Now()
+				release()
 				if err != nil {
 					u.Error("upload error: ", err)
 					op.Err = err.Error()
 				}
 
-				if res.Size != int64(size) && op.Err == "" {
-					err := fmt.Sprint("short upload. want:", size, ", got:", res.Size)
+				if res.Size != objSize && op.Err == "" {
+					err := fmt.Sprint("short upload. want:", objSize, ", got:", res.Size)
 					if op.Err == "" {
 						op.Err = err
 					}
@@ -218,6 +240,11 @@ func (u *PutRDMA) Start(ctx context.Context, wait chan struct{}) (Operations, er
 		}(i)
 	}
 	wg.Wait()
+	u.RateMonitor.logStats()
+	rcv := c.Receiver()
+	for _, op := range u.RateMonitor.statsOps() {
+		rcv <- op
+	}
 	return c.Close(), groupErr
 }
 
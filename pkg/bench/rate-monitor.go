@@ -0,0 +1,275 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/pkg/v2/console"
+)
+
+// RateStats is a snapshot of the throughput a RateMonitor has observed.
+type RateStats struct {
+	// EMA is the exponentially weighted moving average rate, in bytes/sec.
+	EMA float64
+	// Avg is the running average rate since the monitor was created, in bytes/sec.
+	Avg float64
+	// Peak is the highest EMA rate observed, in bytes/sec.
+	Peak float64
+	// Bytes is the total number of bytes accounted for.
+	Bytes int64
+}
+
+// RateMonitor caps aggregate throughput across concurrent workers using a
+// token-bucket waiter, and tracks live throughput as it goes: a per-tick
+// sample rate, an exponential moving average of that sample rate, a
+// running average since start, and the observed peak EMA.
+//
+// A nil *RateMonitor is a valid, no-op monitor: Limit never blocks and
+// Stats returns a zero value. This keeps --bandwidth free of overhead
+// when it isn't set.
+type RateMonitor struct {
+	limit float64 // bytes/sec cap, always > 0 for a non-nil monitor.
+	tau   time.Duration
+
+	mu        sync.Mutex
+	start     time.Time
+	last      time.Time
+	tokens    float64
+	bytes     int64
+	tickStart time.Time
+	tickBytes int64
+	rEMA      float64
+	peak      float64
+}
+
+// NewRateMonitor returns a RateMonitor capping throughput at bytesPerSec.
+// tau is the time constant used to weight the EMA; zero or negative
+// defaults to one second. If bytesPerSec is zero or negative,
+// NewRateMonitor returns nil: callers should treat a nil monitor as
+// "no cap configured".
+func NewRateMonitor(bytesPerSec float64, tau time.Duration) *RateMonitor {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	if tau <= 0 {
+		tau = time.Second
+	}
+	now := time.Now()
+	return &RateMonitor{
+		limit:     bytesPerSec,
+		tau:       tau,
+		start:     now,
+		last:      now,
+		tickStart: now,
+		// Allow an initial burst of up to one time constant worth of data
+		// so the first operation doesn't stall waiting for tokens.
+		tokens: bytesPerSec * tau.Seconds(),
+	}
+}
+
+// Limit blocks until any debt from a previous, larger-than-burst request
+// has been paid down, then unconditionally grants n bytes worth of
+// capacity, putting the bucket into debt itself if n exceeds what's
+// available. This lets a single request larger than the burst size go
+// through in one piece instead of stalling forever waiting for tokens
+// that would never accrue past the burst cap; the next caller pays for
+// it by waiting that much longer. It returns early if ctx is canceled.
+// Calling Limit on a nil *RateMonitor is a no-op.
+func (m *RateMonitor) Limit(ctx context.Context, n int64) time.Duration {
+	if m == nil || n <= 0 {
+		return 0
+	}
+	waitStart := time.Now()
+	m.mu.Lock()
+	m.accrue(time.Now())
+	for m.tokens < 0 {
+		wait := time.Duration(-m.tokens / m.limit * float64(time.Second))
+		m.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return time.Since(waitStart)
+		case <-t.C:
+		}
+
+		m.mu.Lock()
+		m.accrue(time.Now())
+	}
+	m.tokens -= float64(n)
+	m.record(n)
+	m.mu.Unlock()
+	return time.Since(waitStart)
+}
+
+// accrue adds tokens earned since the last call, capped at one time
+// constant worth of idle burst. Debt from a request larger than the
+// burst is left alone here: only the idle positive surplus is clamped,
+// never the negative balance a big request leaves behind. Must be
+// called with m.mu held.
+func (m *RateMonitor) accrue(now time.Time) {
+	elapsed := now.Sub(m.last)
+	m.last = now
+	if elapsed <= 0 {
+		return
+	}
+	burst := m.limit * m.tau.Seconds()
+	m.tokens += elapsed.Seconds() * m.limit
+	if m.tokens > burst {
+		m.tokens = burst
+	}
+}
+
+// record folds n bytes into the throughput samples. Must be called with
+// m.mu held.
+func (m *RateMonitor) record(n int64) {
+	now := time.Now()
+	m.bytes += n
+	m.tickBytes += n
+
+	elapsed := now.Sub(m.tickStart)
+	if elapsed < m.tau/10 {
+		return
+	}
+	rSample := float64(m.tickBytes) / elapsed.Seconds()
+	alpha := 1 - math.Exp(-elapsed.Seconds()/m.tau.Seconds())
+	m.rEMA += alpha * (rSample - m.rEMA)
+	if m.rEMA > m.peak {
+		m.peak = m.rEMA
+	}
+	m.tickBytes = 0
+	m.tickStart = now
+}
+
+// Stats returns a snapshot of the observed throughput. Calling Stats on a
+// nil *RateMonitor returns a zero value.
+func (m *RateMonitor) Stats() RateStats {
+	if m == nil {
+		return RateStats{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var avg float64
+	if elapsed := time.Since(m.start).Seconds(); elapsed > 0 {
+		avg = float64(m.bytes) / elapsed
+	}
+	return RateStats{
+		EMA:   m.rEMA,
+		Avg:   avg,
+		Peak:  m.peak,
+		Bytes: m.bytes,
+	}
+}
+
+// logStats prints the observed steady-state (EMA), average and peak
+// throughput to the console. It is a no-op for a nil monitor, so callers
+// can invoke it unconditionally after a --bandwidth-capped run.
+func (m *RateMonitor) logStats() {
+	if m == nil {
+		return
+	}
+	st := m.Stats()
+	console.Eraseline()
+	console.Info("\rThroughput: ", humanize.Bytes(uint64(st.Avg)), "/s avg, ",
+		humanize.Bytes(uint64(st.EMA)), "/s steady-state, ", humanize.Bytes(uint64(st.Peak)), "/s peak\n")
+}
+
+// rateMonitorOpType marks the synthetic Operations statsOps emits, so
+// warp analyze can tell a recorded bandwidth sample apart from a regular
+// PUT/GET/HEAD/DELETE operation.
+const rateMonitorOpType = "BWSTAT"
+
+// statsOps returns the observed EMA, average and peak throughput as
+// zero-duration synthetic Operations, so feeding these into the same
+// Collector the real operations go through doesn't skew any
+// duration-weighted stat warp analyze computes across the whole
+// Operations slice. The rate in bytes/sec is encoded into File
+// ("ema:<rate>", "avg:<rate>", "peak:<rate>") rather than Size, since
+// Size is what every real PUT/GET/HEAD/DELETE op uses for bytes
+// transferred and these aren't transfers. Returns nil for a nil
+// monitor.
+func (m *RateMonitor) statsOps() []Operation {
+	if m == nil {
+		return nil
+	}
+	st := m.Stats()
+	now := time.Now()
+	mk := func(stat string, rate float64) Operation {
+		return Operation{
+			OpType: rateMonitorOpType,
+			File:   fmt.Sprintf("%s:%d", stat, int64(rate)),
+			Start:  now,
+			End:    now,
+		}
+	}
+	return []Operation{
+		mk("ema", st.EMA),
+		mk("avg", st.Avg),
+		mk("peak", st.Peak),
+	}
+}
+
+// bandwidthUnits maps bits/sec suffixes to their decimal factor. Checked
+// longest-suffix-first so "kbps" isn't mistaken for a plain "bps" rate.
+var bandwidthUnits = []struct {
+	suffix string
+	factor float64
+}{
+	{"tbps", 1e12},
+	{"gbps", 1e9},
+	{"mbps", 1e6},
+	{"kbps", 1e3},
+	{"bps", 1},
+}
+
+// ParseBandwidth parses a human-readable bandwidth string into a rate in
+// bytes/sec. Both byte-based sizes ("100MiB", "10GB", meaning bytes/sec)
+// and bit-rate suffixes ("10Gbps", "100Mbps") are accepted; an empty
+// string parses to 0 with no error, meaning "no cap".
+func ParseBandwidth(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	lower := strings.ToLower(s)
+	for _, u := range bandwidthUnits {
+		if strings.HasSuffix(lower, u.suffix) {
+			numPart := strings.TrimSpace(s[:len(s)-len(u.suffix)])
+			v, err := strconv.ParseFloat(numPart, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid bandwidth %q: %v", s, err)
+			}
+			return v * u.factor / 8, nil
+		}
+	}
+	n, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bandwidth %q: %v", s, err)
+	}
+	return float64(n), nil
+}
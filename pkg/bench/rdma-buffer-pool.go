@@ -0,0 +1,129 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/dustin/go-humanize"
+	"github.com/minio/minio-go/v7"
+)
+
+// RDMABufferPool hands out aligned RDMA buffers to benchmark workers,
+// bucketed by power-of-two size class. Nothing is allocated up front: a
+// buffer is allocated the first time Get finds no free buffer of its
+// size class, and is kept for reuse once released. Reusing registered
+// memory across operations lets --obj.randsize vary the object size per
+// op without registering a new buffer with the NIC on every operation,
+// and bounds the total registered memory a benchmark can pin via
+// --rdma-pool-mem.
+//
+// A RDMABufferPool is safe for concurrent use.
+type RDMABufferPool struct {
+	mu      sync.Mutex
+	gpu     bool
+	maxMem  uint64
+	used    uint64
+	classes map[int][]unsafe.Pointer
+}
+
+// NewRDMABufferPool creates a pool of GPU or host buffers. maxMem bounds
+// the total registered memory the pool will allocate across all size
+// classes; a maxMem of 0 leaves the pool unbounded.
+func NewRDMABufferPool(maxMem uint64, gpu bool) *RDMABufferPool {
+	return &RDMABufferPool{
+		gpu:     gpu,
+		maxMem:  maxMem,
+		classes: make(map[int][]unsafe.Pointer),
+	}
+}
+
+// sizeClass rounds size up to the next power of two, so that a bounded
+// number of buffer sizes is ever registered regardless of how finely
+// --obj.randsize varies the requested size.
+func sizeClass(size int) int {
+	if size <= 1 {
+		return 1
+	}
+	c := 1
+	for c < size {
+		c <<= 1
+	}
+	return c
+}
+
+// Get returns a buffer able to hold at least size bytes, and a release
+// function that returns the buffer to the pool for reuse. The caller
+// must call release exactly once, after it is done with the buffer.
+//
+// If satisfying the request would grow the pool past its configured
+// --rdma-pool-mem cap, Get returns an error instead of allocating past
+// it.
+func (p *RDMABufferPool) Get(size int) (unsafe.Pointer, func(), error) {
+	class := sizeClass(size)
+
+	p.mu.Lock()
+	if free := p.classes[class]; len(free) > 0 {
+		buf := free[len(free)-1]
+		p.classes[class] = free[:len(free)-1]
+		p.mu.Unlock()
+		return buf, func() { p.release(class, buf) }, nil
+	}
+	if p.maxMem > 0 && p.used+uint64(class) > p.maxMem {
+		p.mu.Unlock()
+		return nil, nil, fmt.Errorf("rdma buffer pool: allocating a %s buffer would exceed the --rdma-pool-mem cap of %s",
+			humanize.IBytes(uint64(class)), humanize.IBytes(p.maxMem))
+	}
+	p.used += uint64(class)
+	p.mu.Unlock()
+
+	var buf unsafe.Pointer
+	if p.gpu {
+		buf = minio.AlignedGPU(class, ' ')
+	} else {
+		buf = minio.Aligned(class, ' ')
+	}
+	return buf, func() { p.release(class, buf) }, nil
+}
+
+func (p *RDMABufferPool) release(class int, buf unsafe.Pointer) {
+	p.mu.Lock()
+	p.classes[class] = append(p.classes[class], buf)
+	p.mu.Unlock()
+}
+
+// Close frees every buffer currently sitting in the pool's free lists.
+// It must only be called once all buffers handed out by Get have been
+// released, and the pool must not be used afterwards.
+func (p *RDMABufferPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for class, bufs := range p.classes {
+		for _, buf := range bufs {
+			if p.gpu {
+				minio.FreeGPU(buf)
+			} else {
+				minio.Free(buf)
+			}
+		}
+		delete(p.classes, class)
+	}
+	p.used = 0
+}
@@ -0,0 +1,130 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestSizeClass(t *testing.T) {
+	cases := map[int]int{
+		0:      1,
+		1:      1,
+		2:      2,
+		3:      4,
+		1024:   1024,
+		1025:   2048,
+		100000: 131072,
+	}
+	for size, want := range cases {
+		if got := sizeClass(size); got != want {
+			t.Errorf("sizeClass(%d) = %d, want %d", size, got, want)
+		}
+	}
+}
+
+// TestRDMABufferPoolConcurrent hammers Get/release concurrently and
+// verifies that no buffer is ever handed out to two callers at once, and
+// that the pool settles on a stable steady-state allocation count once
+// warmed up.
+func TestRDMABufferPoolConcurrent(t *testing.T) {
+	const (
+		workers    = 32
+		iterations = 200
+	)
+	p := NewRDMABufferPool(0, false)
+	defer p.Close()
+
+	var mu sync.Mutex
+	checkedOut := map[unsafe.Pointer]bool{}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			size := 1 << uint(w%12)
+			for i := 0; i < iterations; i++ {
+				buf, release, err := p.Get(size)
+				if err != nil {
+					t.Errorf("Get(%d): %v", size, err)
+					return
+				}
+
+				mu.Lock()
+				if checkedOut[buf] {
+					mu.Unlock()
+					t.Errorf("buffer %p handed out twice concurrently", buf)
+					return
+				}
+				checkedOut[buf] = true
+				mu.Unlock()
+
+				mu.Lock()
+				delete(checkedOut, buf)
+				mu.Unlock()
+				release()
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	steadyState := p.used
+	p.mu.Unlock()
+
+	// A second pass should reuse the warmed-up free lists and not grow
+	// the pool's total allocation any further.
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			size := 1 << uint(w%12)
+			_, release, err := p.Get(size)
+			if err != nil {
+				t.Errorf("Get(%d): %v", size, err)
+				return
+			}
+			release()
+		}(w)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.used != steadyState {
+		t.Errorf("pool allocation grew after warm-up: got %d, want %d", p.used, steadyState)
+	}
+}
+
+func TestRDMABufferPoolRefusesOverCap(t *testing.T) {
+	p := NewRDMABufferPool(1024, false)
+	defer p.Close()
+
+	_, release, err := p.Get(1024)
+	if err != nil {
+		t.Fatalf("Get(1024): %v", err)
+	}
+	defer release()
+
+	if _, _, err := p.Get(1024); err == nil {
+		t.Fatal("expected Get to refuse allocation past the pool's maxMem cap")
+	}
+}
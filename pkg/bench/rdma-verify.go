@@ -0,0 +1,47 @@
+/*
+ * Warp (C) 2024 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"hash/fnv"
+	"unsafe"
+)
+
+// bufferBytes views a registered RDMA buffer as a byte slice, without
+// copying, so its contents can be seeded or checked with ordinary Go
+// code.
+func bufferBytes(buf unsafe.Pointer, size int) []byte {
+	if size <= 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(buf), size)
+}
+
+// verifyPattern fills dst with a byte pattern deterministically derived
+// from name and the absolute offset of dst[0] within the object. Because
+// the pattern only depends on the object name and absolute offset, any
+// byte range of a --verify object can be independently regenerated and
+// compared, which is what lets ranged GETs be checksummed.
+func verifyPattern(dst []byte, name string, offset int64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	seed := h.Sum64()
+	for i := range dst {
+		dst[i] = byte(seed + uint64(offset) + uint64(i))
+	}
+}